@@ -16,19 +16,30 @@ import (
 	"gopkg.in/square/go-jose.v2/jwt"
 )
 
+// commaSlice is a flag.Value for comma-separated lists, e.g. -aud a,b,c.
+type commaSlice []string
+
+func (s *commaSlice) String() string { return strings.Join(*s, ",") }
+
+func (s *commaSlice) Set(v string) error {
+	*s = strings.Split(v, ",")
+	return nil
+}
+
 type serviceAccount struct {
 	// Standard claims (as specified in RFC 7519).
 	jwt.Claims
 	// Pomerium claims (not standard claims)
-	Email             string   `json:"email"`
-	Groups            []string `json:"groups,omitempty"`
-	User              string   `json:"user,omitempty"`
-	ImpersonateEmail  string   `json:"impersonate_email,omitempty"`
-	ImpersonateGroups []string `json:"impersonate_groups,omitempty"`
+	Email             string           `json:"email"`
+	Groups            []string         `json:"groups,omitempty"`
+	User              string           `json:"user,omitempty"`
+	ImpersonateEmail  string           `json:"impersonate_email,omitempty"`
+	ImpersonateGroups []string         `json:"impersonate_groups,omitempty"`
+	Access            []ResourceAction `json:"access,omitempty"`
 }
 
 func main() {
-	if err := run(); err != nil {
+	if err := run(os.Args[1:]); err != nil {
 		fmt.Fprintf(os.Stderr, color.RedString("\n⛔️ %s\n\n"), err)
 		printHelp(flags)
 		os.Exit(1)
@@ -36,18 +47,39 @@ func main() {
 	os.Exit(0)
 }
 
+// run dispatches to a subcommand, falling back to the legacy flags-only
+// invocation.
+func run(args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "jwks":
+			return runJWKS(args[1:])
+		case "verify":
+			return runVerify(args[1:])
+		case "mint":
+			return runMintProfile(args[1:])
+		case "serve":
+			return runServe(args[1:])
+		}
+	}
+	return runMint(args)
+}
+
 var flags *flag.FlagSet
 
-func run() error {
+func runMint(args []string) error {
 	var sa serviceAccount
 
 	// temporary variables we will use to hydrate our service account
 	// struct from basic types pulled in from our flags
 	var (
-		aud               xstrings.CommaSlice
-		groups            xstrings.CommaSlice
-		impersonateGroups xstrings.CommaSlice
+		aud               commaSlice
+		groups            commaSlice
+		impersonateGroups commaSlice
 		expiry            time.Duration
+		alg               string
+		signingKeyPath    string
+		scopes            scopeList
 	)
 
 	// set our JWT claims from the supplied CLI flags
@@ -60,36 +92,58 @@ func run() error {
 	flags.Var(&aud, "aud", "Audience (e.g. httpbin.int.pomerium.io,prometheus.int.pomerium.io)")
 	flags.Var(&groups, "groups", "Groups (e.g. admins@pomerium.io,users@pomerium.io)")
 	flags.Var(&impersonateGroups, "impersonate_groups", "Impersonation Groups (optional)")
+	flags.Var(&scopes, "scope", "Scoped access claim, repeatable (e.g. repository:internal/httpbin:pull,push)")
 	flags.DurationVar(&expiry, "expiry", time.Hour, "Expiry")
+	flags.StringVar(&alg, "alg", "HS256", "Signing algorithm (HS256, RS256, ES256, EdDSA)")
+	flags.StringVar(&signingKeyPath, "signing-key", "", "Path to a PEM encoded private key (RS256, ES256, or EdDSA; replaces the shared key)")
 
 	// hydrate the sessions non-primate types
-	if err := flags.Parse(os.Args[1:]); err != nil {
+	if err := flags.Parse(args); err != nil {
 		return err
 	}
 	sa.Audience = jwt.Audience(aud)
 	sa.Groups = []string(groups)
 	sa.ImpersonateGroups = []string(impersonateGroups)
+	sa.Access = []ResourceAction(scopes)
 	sa.Expiry = jwt.NewNumericDate(time.Now().Add(expiry))
 	sa.IssuedAt = jwt.NewNumericDate(time.Now())
 	sa.NotBefore = jwt.NewNumericDate(time.Now())
 	// why not be pretty?
 	c := color.New(color.FgGreen)
-	// check that we've got our shared key to sign our jwt
-	var sharedKey string
-	args := flags.Args()
-	if len(args) == 1 {
-		sharedKey = args[0]
-	} else {
-		if _, err := c.Println("Enter base64 encoded shared key >"); err != nil {
-			return err
-		}
-		scanner := bufio.NewScanner(os.Stdin)
-		scanner.Scan()
-		sharedKey = scanner.Text()
+
+	sigAlg, ok := signingAlgorithms[alg]
+	if !ok {
+		return fmt.Errorf("unsupported -alg %q", alg)
 	}
 
-	if sharedKey == "" {
-		return errors.New("shared key required")
+	// the shared key is only used for HS256; asymmetric algorithms sign
+	// with the private key pointed to by -signing-key instead
+	var sharedKey []byte
+	if sigAlg == jose.HS256 {
+		var encodedKey string
+		cliArgs := flags.Args()
+		if len(cliArgs) == 1 {
+			encodedKey = cliArgs[0]
+		} else {
+			if _, err := c.Println("Enter base64 encoded shared key >"); err != nil {
+				return err
+			}
+			scanner := bufio.NewScanner(os.Stdin)
+			scanner.Scan()
+			encodedKey = scanner.Text()
+		}
+
+		if encodedKey == "" {
+			return errors.New("shared key required")
+		}
+
+		var err error
+		sharedKey, err = base64.StdEncoding.DecodeString(encodedKey)
+		if err != nil {
+			return fmt.Errorf("shared key not base64: %w", err)
+		}
+	} else if signingKeyPath == "" {
+		return errors.New("-signing-key is required for " + alg)
 	}
 
 	if sa.Email == "" {
@@ -104,14 +158,9 @@ func run() error {
 		return errors.New("iss is required")
 	}
 
-	decodedKey, err := base64.StdEncoding.DecodeString(sharedKey)
+	signer, err := newSigner(sigAlg, sharedKey, signingKeyPath)
 	if err != nil {
-		return fmt.Errorf("shared key not base64: %w", err)
-	}
-
-	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.HS256, Key: decodedKey}, nil)
-	if err != nil {
-		return fmt.Errorf("bad shared key: %w", err)
+		return err
 	}
 	raw, err := jwt.Signed(signer).Claims(sa).CompactSerialize()
 	if err != nil {
@@ -135,13 +184,36 @@ func run() error {
 
 func printHelp(fs *flag.FlagSet) {
 	fmt.Fprintf(os.Stderr, strings.TrimSpace(help)+"\n\n", os.Args[0])
-	fs.PrintDefaults()
+	if fs != nil {
+		fs.PrintDefaults()
+	}
 }
 
 const help = `
-pomerium-sa generates a pomerium service account from a shared key.
+pomerium-sa generates a pomerium service account from a shared key or a
+private signing key, and can publish the corresponding JWKS.
 
 Usage: %[1]s [flags] [base64'd shared secret setting]
+       %[1]s jwks -signing-key <path> -alg <RS256|ES256|EdDSA>
+       %[1]s verify [-token <jwt>] -iss <issuer> (-shared-key <key> | -key <path> | -jwks-url <url>)
+       %[1]s mint <profile> -config <path> -email=... [flags]
+       %[1]s mint -all <host> -config <path> -email=... [flags]
+       %[1]s serve -key-dir <dir> [-addr :8080]
+
+serve exposes POST /token, GET /.well-known/jwks.json, and GET /healthz.
+Requests to /token must carry "Authorization: Bearer $POMERIUM_SA_BOOTSTRAP_TOKEN".
+Keys are loaded from -key-dir and hot-reloaded on SIGHUP or file change; the
+newest key signs new tokens while older keys stay in the jwks during rotation.
+
+-scope adds a scoped access claim, e.g.:
+
+	-scope repository:internal/httpbin:pull,push
+
+produces an "access" claim of:
+
+	"access": [
+	  {"type": "repository", "name": "internal/httpbin", "actions": ["pull", "push"]}
+	]
 
 For additional help see:
 
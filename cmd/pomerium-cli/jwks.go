@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"gopkg.in/square/go-jose.v2"
+)
+
+// runJWKS reads the private key used for asymmetric signing and emits a
+// JWKS document describing the corresponding public key, so an authenticate
+// service can verify JWTs minted with -alg RS256/ES256/EdDSA.
+func runJWKS(args []string) error {
+	fs := flag.NewFlagSet("pomerium-sa jwks", flag.ExitOnError)
+	flags = fs
+	signingKeyPath := fs.String("signing-key", "", "Path to a PEM encoded private key (RS256, ES256, or EdDSA)")
+	alg := fs.String("alg", "RS256", "Signing algorithm (RS256, ES256, EdDSA)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	sigAlg, ok := signingAlgorithms[*alg]
+	if !ok || sigAlg == jose.HS256 {
+		return fmt.Errorf("unsupported -alg %q for jwks", *alg)
+	}
+	if *signingKeyPath == "" {
+		return fmt.Errorf("-signing-key is required")
+	}
+
+	priv, err := loadPrivateKey(*signingKeyPath)
+	if err != nil {
+		return err
+	}
+	kid, err := keyID(priv.Public())
+	if err != nil {
+		return err
+	}
+
+	set := jose.JSONWebKeySet{
+		Keys: []jose.JSONWebKey{
+			{
+				Key:       priv.Public(),
+				KeyID:     kid,
+				Algorithm: string(sigAlg),
+				Use:       "sig",
+			},
+		},
+	}
+
+	enc, err := json.MarshalIndent(set, "", " ")
+	if err != nil {
+		return fmt.Errorf("couldn't marshal jwks: %w", err)
+	}
+	fmt.Fprintf(os.Stdout, "%s\n", enc)
+	return nil
+}
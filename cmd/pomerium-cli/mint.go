@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// signingProfile is one named entry of a -config file.
+type signingProfile struct {
+	Issuer     string   `yaml:"issuer"`
+	Audience   []string `yaml:"audience"`
+	Expiry     string   `yaml:"expiry"`
+	SigningKey string   `yaml:"signing_key"`
+	Algorithm  string   `yaml:"algorithm"`
+}
+
+type profileConfig struct {
+	Profiles map[string]signingProfile `yaml:"profiles"`
+}
+
+func loadProfileConfig(path string) (*profileConfig, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config: %w", err)
+	}
+	var cfg profileConfig
+	if err := yaml.Unmarshal(raw, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing config: %w", err)
+	}
+	return &cfg, nil
+}
+
+// runMintProfile implements `pomerium-sa mint <profile> -email=...` and its
+// `-all <host>` variant.
+func runMintProfile(args []string) error {
+	fs := flag.NewFlagSet("pomerium-sa mint", flag.ExitOnError)
+	flags = fs
+	configPath := fs.String("config", "", "Path to a YAML or JSON file defining signing profiles")
+	all := fs.String("all", "", "Mint one token per profile whose audience contains this host, instead of a single named profile")
+	var sa serviceAccount
+	fs.StringVar(&sa.Email, "email", "", "Email")
+	fs.StringVar(&sa.ImpersonateEmail, "impersonate_email", "", "Impersonation Email (optional)")
+	fs.StringVar(&sa.Subject, "sub", "", "Subject (typically User's GUID)")
+	fs.StringVar(&sa.User, "user", "", "User (typically User's GUID)")
+	var groups, impersonateGroups commaSlice
+	var scopes scopeList
+	fs.Var(&groups, "groups", "Groups (e.g. admins@pomerium.io,users@pomerium.io)")
+	fs.Var(&impersonateGroups, "impersonate_groups", "Impersonation Groups (optional)")
+	fs.Var(&scopes, "scope", "Scoped access claim, repeatable (e.g. repository:internal/httpbin:pull,push)")
+
+	// the profile name is a bare positional argument, e.g. `mint prod -email=...`
+	var profileName string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		profileName = args[0]
+		args = args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *configPath == "" {
+		return errors.New("-config is required")
+	}
+	if profileName == "" && *all == "" {
+		return errors.New("a profile name or -all <host> is required")
+	}
+	if sa.Email == "" {
+		return errors.New("email is required")
+	}
+
+	cfg, err := loadProfileConfig(*configPath)
+	if err != nil {
+		return err
+	}
+
+	sa.Groups = []string(groups)
+	sa.ImpersonateGroups = []string(impersonateGroups)
+	sa.Access = []ResourceAction(scopes)
+
+	var names []string
+	if *all != "" {
+		for name, p := range cfg.Profiles {
+			if containsHost(p.Audience, *all) {
+				names = append(names, name)
+			}
+		}
+		if len(names) == 0 {
+			return fmt.Errorf("no profile audience contains %q", *all)
+		}
+		sort.Strings(names)
+	} else {
+		if _, ok := cfg.Profiles[profileName]; !ok {
+			return fmt.Errorf("no profile named %q in %s", profileName, *configPath)
+		}
+		names = []string{profileName}
+	}
+
+	c := color.New(color.FgGreen)
+	for _, name := range names {
+		token, claims, err := mintFromProfile(cfg.Profiles[name], sa)
+		if err != nil {
+			return fmt.Errorf("profile %q: %w", name, err)
+		}
+		claimsJSON, err := json.MarshalIndent(claims, "", " ")
+		if err != nil {
+			return fmt.Errorf("couldn't pretty print jwt: %w", err)
+		}
+		if _, err := c.Printf("Service Account (%s)\n", name); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stdout, "%s\n\n", claimsJSON)
+		if _, err := c.Println("JWT 🍪"); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stdout, "%s\n\n", token)
+	}
+	return nil
+}
+
+func containsHost(audience []string, host string) bool {
+	for _, a := range audience {
+		if a == host {
+			return true
+		}
+	}
+	return false
+}
+
+// mintFromProfile fills in the issuer, audience, and expiry from profile
+// and signs with its key material.
+func mintFromProfile(profile signingProfile, sa serviceAccount) (string, serviceAccount, error) {
+	if profile.Issuer == "" {
+		return "", sa, errors.New("profile has no issuer")
+	}
+	sigAlg, ok := signingAlgorithms[profile.Algorithm]
+	if !ok {
+		return "", sa, fmt.Errorf("unsupported algorithm %q", profile.Algorithm)
+	}
+
+	expiry := time.Hour
+	if profile.Expiry != "" {
+		d, err := time.ParseDuration(profile.Expiry)
+		if err != nil {
+			return "", sa, fmt.Errorf("bad expiry: %w", err)
+		}
+		expiry = d
+	}
+
+	sa.Issuer = profile.Issuer
+	sa.Audience = jwt.Audience(profile.Audience)
+	sa.Expiry = jwt.NewNumericDate(time.Now().Add(expiry))
+	sa.IssuedAt = jwt.NewNumericDate(time.Now())
+	sa.NotBefore = jwt.NewNumericDate(time.Now())
+
+	var (
+		sharedKey      []byte
+		signingKeyPath string
+		err            error
+	)
+	if sigAlg == jose.HS256 {
+		sharedKey, err = base64.StdEncoding.DecodeString(profile.SigningKey)
+		if err != nil {
+			return "", sa, fmt.Errorf("signing_key not base64: %w", err)
+		}
+	} else {
+		signingKeyPath = profile.SigningKey
+	}
+
+	signer, err := newSigner(sigAlg, sharedKey, signingKeyPath)
+	if err != nil {
+		return "", sa, err
+	}
+	raw, err := jwt.Signed(signer).Claims(sa).CompactSerialize()
+	if err != nil {
+		return "", sa, fmt.Errorf("couldn't sign jwt: %w", err)
+	}
+	return raw, sa, nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+func TestLoadProfileConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	const doc = `
+profiles:
+  prod:
+    issuer: authenticate.corp.example.com
+    audience: [httpbin.corp.example.com]
+    expiry: 1h
+    signing_key: c2VjcmV0
+    algorithm: HS256
+`
+	if err := os.WriteFile(path, []byte(doc), 0o600); err != nil {
+		t.Fatalf("writing config: %s", err)
+	}
+
+	cfg, err := loadProfileConfig(path)
+	if err != nil {
+		t.Fatalf("loadProfileConfig: %s", err)
+	}
+	prod, ok := cfg.Profiles["prod"]
+	if !ok {
+		t.Fatal(`expected a "prod" profile`)
+	}
+	if prod.Issuer != "authenticate.corp.example.com" {
+		t.Errorf("Issuer = %q", prod.Issuer)
+	}
+	if prod.Algorithm != "HS256" {
+		t.Errorf("Algorithm = %q", prod.Algorithm)
+	}
+}
+
+func TestMintFromProfileHS256(t *testing.T) {
+	profile := signingProfile{
+		Issuer:     "authenticate.corp.example.com",
+		Audience:   []string{"httpbin.corp.example.com"},
+		Expiry:     "1h",
+		SigningKey: "c2VjcmV0", // base64("secret")
+		Algorithm:  "HS256",
+	}
+	sa := serviceAccount{Email: "user@example.com"}
+
+	raw, signed, err := mintFromProfile(profile, sa)
+	if err != nil {
+		t.Fatalf("mintFromProfile: %s", err)
+	}
+	if signed.Issuer != profile.Issuer {
+		t.Errorf("Issuer = %q, want %q", signed.Issuer, profile.Issuer)
+	}
+
+	parsed, err := jwt.ParseSigned(raw)
+	if err != nil {
+		t.Fatalf("parsing jwt: %s", err)
+	}
+	var got serviceAccount
+	if err := parsed.Claims([]byte("secret"), &got); err != nil {
+		t.Fatalf("invalid signature: %s", err)
+	}
+	if got.Email != sa.Email {
+		t.Errorf("Email = %q, want %q", got.Email, sa.Email)
+	}
+}
+
+func TestMintFromProfileUnsupportedAlgorithm(t *testing.T) {
+	profile := signingProfile{Issuer: "authenticate.corp.example.com", Algorithm: "none"}
+	if _, _, err := mintFromProfile(profile, serviceAccount{}); err == nil {
+		t.Fatal("expected an error for an unsupported algorithm")
+	}
+}
+
+func TestContainsHost(t *testing.T) {
+	if !containsHost([]string{"a", "b"}, "b") {
+		t.Error("expected containsHost to find b")
+	}
+	if containsHost([]string{"a", "b"}, "c") {
+		t.Error("expected containsHost not to find c")
+	}
+}
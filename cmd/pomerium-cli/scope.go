@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ResourceAction is a Docker/Harbor-style scoped permission claim, e.g.
+// {Type: "repository", Name: "internal/httpbin", Actions: ["pull", "push"]}.
+type ResourceAction struct {
+	Type    string   `json:"type"`
+	Name    string   `json:"name"`
+	Actions []string `json:"actions"`
+}
+
+// scopeList collects repeated -scope flags, each of the form
+// "type:name:action1,action2", into ResourceAction claims.
+type scopeList []ResourceAction
+
+func (s *scopeList) String() string {
+	return fmt.Sprint([]ResourceAction(*s))
+}
+
+func (s *scopeList) Set(v string) error {
+	ra, err := parseScope(v)
+	if err != nil {
+		return err
+	}
+	*s = append(*s, ra)
+	return nil
+}
+
+// parseScope parses "type:name:action1,action2" into a ResourceAction. name
+// is split on its last colon rather than its first, so it may itself
+// contain colons (e.g. a registry host:port/path).
+func parseScope(v string) (ResourceAction, error) {
+	badFormat := fmt.Errorf("-scope %q must be of the form type:name:action1,action2", v)
+
+	typeSep := strings.Index(v, ":")
+	if typeSep < 0 {
+		return ResourceAction{}, badFormat
+	}
+	typ, rest := v[:typeSep], v[typeSep+1:]
+
+	nameSep := strings.LastIndex(rest, ":")
+	if nameSep < 0 {
+		return ResourceAction{}, badFormat
+	}
+	name, actionsPart := rest[:nameSep], rest[nameSep+1:]
+
+	if typ == "" || name == "" || actionsPart == "" {
+		return ResourceAction{}, badFormat
+	}
+
+	actions := strings.Split(actionsPart, ",")
+	for _, a := range actions {
+		if a == "" {
+			return ResourceAction{}, fmt.Errorf("-scope %q has an empty action", v)
+		}
+	}
+
+	return ResourceAction{Type: typ, Name: name, Actions: actions}, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseScope(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    ResourceAction
+		wantErr bool
+	}{
+		{
+			in:   "repository:internal/httpbin:pull,push",
+			want: ResourceAction{Type: "repository", Name: "internal/httpbin", Actions: []string{"pull", "push"}},
+		},
+		{
+			in:   "repository:registry.example.com:5000/path:pull",
+			want: ResourceAction{Type: "repository", Name: "registry.example.com:5000/path", Actions: []string{"pull"}},
+		},
+		{in: "repository:internal/httpbin:", wantErr: true},
+		{in: "repository:internal/httpbin:pull,", wantErr: true},
+		{in: "repository:internal/httpbin", wantErr: true},
+		{in: "repository", wantErr: true},
+		{in: ":internal/httpbin:pull", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseScope(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseScope(%q): expected error, got %+v", tt.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseScope(%q): unexpected error: %s", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseScope(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestScopeListSet(t *testing.T) {
+	var s scopeList
+	if err := s.Set("repository:internal/httpbin:pull,push"); err != nil {
+		t.Fatalf("Set: %s", err)
+	}
+	if err := s.Set("repository:internal/other:pull"); err != nil {
+		t.Fatalf("Set: %s", err)
+	}
+	if len(s) != 2 {
+		t.Fatalf("len(s) = %d, want 2", len(s))
+	}
+	if err := s.Set("repository:internal/httpbin:"); err == nil {
+		t.Fatalf("Set: expected error for missing action")
+	}
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+// bootstrapTokenEnv holds the bearer token callers must present to /token.
+const bootstrapTokenEnv = "POMERIUM_SA_BOOTSTRAP_TOKEN"
+
+// keyEntry is one signing key loaded from -key-dir.
+type keyEntry struct {
+	alg     jose.SignatureAlgorithm
+	kid     string
+	priv    crypto.Signer
+	modTime time.Time
+}
+
+// keyRing is the hot-reloadable set of keys loaded from a directory; the
+// newest by file modification time signs new tokens, and all are published
+// in the jwks so tokens signed just before a rotation keep verifying.
+type keyRing struct {
+	mu      sync.RWMutex
+	current *keyEntry
+	all     []*keyEntry
+}
+
+func loadKeyRing(dir string) (*keyRing, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading key directory: %w", err)
+	}
+
+	var entries []*keyEntry
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".pem") {
+			continue
+		}
+		path := filepath.Join(dir, f.Name())
+		priv, err := loadPrivateKey(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		alg, err := algorithmFor(priv)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		kid, err := keyID(priv.Public())
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		info, err := f.Info()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		entries = append(entries, &keyEntry{alg: alg, kid: kid, priv: priv, modTime: info.ModTime()})
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no *.pem keys found in %s", dir)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].modTime.Before(entries[j].modTime) })
+
+	return &keyRing{current: entries[len(entries)-1], all: entries}, nil
+}
+
+// algorithmFor infers the signing algorithm from a private key's Go type.
+func algorithmFor(priv crypto.Signer) (jose.SignatureAlgorithm, error) {
+	switch priv.(type) {
+	case *rsa.PrivateKey:
+		return jose.RS256, nil
+	case *ecdsa.PrivateKey:
+		return jose.ES256, nil
+	case ed25519.PrivateKey:
+		return jose.EdDSA, nil
+	default:
+		return "", fmt.Errorf("unsupported key type %T", priv)
+	}
+}
+
+func (r *keyRing) replace(other *keyRing) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.current = other.current
+	r.all = other.all
+}
+
+func (r *keyRing) signingKey() *keyEntry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.current
+}
+
+func (r *keyRing) jwks() jose.JSONWebKeySet {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var set jose.JSONWebKeySet
+	for _, e := range r.all {
+		set.Keys = append(set.Keys, jose.JSONWebKey{
+			Key:       e.priv.Public(),
+			KeyID:     e.kid,
+			Algorithm: string(e.alg),
+			Use:       "sig",
+		})
+	}
+	return set
+}
+
+// runServe turns pomerium-sa into a minting microservice.
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("pomerium-sa serve", flag.ExitOnError)
+	flags = fs
+	keyDir := fs.String("key-dir", "", "Directory of PEM encoded private keys to sign with and publish in the jwks")
+	addr := fs.String("addr", ":8080", "Address to listen on")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *keyDir == "" {
+		return errors.New("-key-dir is required")
+	}
+	bootstrapToken := os.Getenv(bootstrapTokenEnv)
+	if bootstrapToken == "" {
+		return fmt.Errorf("%s must be set", bootstrapTokenEnv)
+	}
+
+	ring, err := loadKeyRing(*keyDir)
+	if err != nil {
+		return err
+	}
+
+	reload := func() {
+		next, err := loadKeyRing(*keyDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "reloading keys from %s: %s\n", *keyDir, err)
+			return
+		}
+		ring.replace(next)
+		fmt.Fprintf(os.Stdout, "reloaded signing keys from %s\n", *keyDir)
+	}
+
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	go func() {
+		for range sighup {
+			reload()
+		}
+	}()
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("starting file watcher: %w", err)
+	}
+	defer watcher.Close()
+	if err := watcher.Add(*keyDir); err != nil {
+		return fmt.Errorf("watching %s: %w", *keyDir, err)
+	}
+	go func() {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) != 0 {
+					reload()
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				fmt.Fprintf(os.Stderr, "watching %s: %s\n", *keyDir, err)
+			}
+		}
+	}()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/.well-known/jwks.json", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(ring.jwks())
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		serveToken(w, r, ring, bootstrapToken)
+	})
+
+	fmt.Fprintf(os.Stdout, "pomerium-sa listening on %s\n", *addr)
+	return http.ListenAndServe(*addr, mux)
+}
+
+// tokenRequest is the JSON body accepted by POST /token.
+type tokenRequest struct {
+	Email             string           `json:"email"`
+	Subject           string           `json:"sub"`
+	User              string           `json:"user"`
+	Issuer            string           `json:"iss"`
+	Audience          []string         `json:"aud"`
+	Groups            []string         `json:"groups"`
+	ImpersonateEmail  string           `json:"impersonate_email"`
+	ImpersonateGroups []string         `json:"impersonate_groups"`
+	Access            []ResourceAction `json:"access"`
+	Expiry            string           `json:"expiry"`
+}
+
+func serveToken(w http.ResponseWriter, r *http.Request, ring *keyRing, bootstrapToken string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	presented, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(presented), []byte(bootstrapToken)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req tokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("bad request: %s", err), http.StatusBadRequest)
+		return
+	}
+	if req.Email == "" || req.Issuer == "" || len(req.Audience) == 0 {
+		http.Error(w, "email, iss, and aud are required", http.StatusBadRequest)
+		return
+	}
+
+	expiry := time.Hour
+	if req.Expiry != "" {
+		d, err := time.ParseDuration(req.Expiry)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("bad expiry: %s", err), http.StatusBadRequest)
+			return
+		}
+		expiry = d
+	}
+
+	entry := ring.signingKey()
+	if entry == nil {
+		http.Error(w, "no signing key loaded", http.StatusInternalServerError)
+		return
+	}
+
+	sa := serviceAccount{
+		Email:             req.Email,
+		User:              req.User,
+		Groups:            req.Groups,
+		ImpersonateEmail:  req.ImpersonateEmail,
+		ImpersonateGroups: req.ImpersonateGroups,
+		Access:            req.Access,
+	}
+	sa.Issuer = req.Issuer
+	sa.Subject = req.Subject
+	sa.Audience = jwt.Audience(req.Audience)
+	sa.Expiry = jwt.NewNumericDate(time.Now().Add(expiry))
+	sa.IssuedAt = jwt.NewNumericDate(time.Now())
+	sa.NotBefore = jwt.NewNumericDate(time.Now())
+
+	so := (&jose.SignerOptions{}).WithHeader("kid", entry.kid)
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: entry.alg, Key: entry.priv}, so)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	raw, err := jwt.Signed(signer).Claims(sa).CompactSerialize()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"token": raw})
+}
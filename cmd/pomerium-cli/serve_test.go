@@ -0,0 +1,79 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeTestKey(t *testing.T, dir, name string, modTime time.Time) string {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generating key: %s", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatalf("marshaling key: %s", err)
+	}
+	path := filepath.Join(dir, name)
+	block := &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatalf("writing key: %s", err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("setting mtime: %s", err)
+	}
+	return path
+}
+
+func TestLoadKeyRingPicksNewestKey(t *testing.T) {
+	dir := t.TempDir()
+	older := writeTestKey(t, dir, "older.pem", time.Now().Add(-time.Hour))
+	newer := writeTestKey(t, dir, "newer.pem", time.Now())
+	_ = older
+
+	ring, err := loadKeyRing(dir)
+	if err != nil {
+		t.Fatalf("loadKeyRing: %s", err)
+	}
+	if len(ring.all) != 2 {
+		t.Fatalf("len(ring.all) = %d, want 2", len(ring.all))
+	}
+
+	want, err := loadPrivateKey(newer)
+	if err != nil {
+		t.Fatalf("loadPrivateKey: %s", err)
+	}
+	wantKID, err := keyID(want.Public())
+	if err != nil {
+		t.Fatalf("keyID: %s", err)
+	}
+	if ring.current.kid != wantKID {
+		t.Errorf("ring.current.kid = %q, want %q (the newer key)", ring.current.kid, wantKID)
+	}
+}
+
+func TestLoadKeyRingEmptyDir(t *testing.T) {
+	if _, err := loadKeyRing(t.TempDir()); err == nil {
+		t.Fatal("expected an error for a directory with no keys")
+	}
+}
+
+func TestKeyRingReplace(t *testing.T) {
+	a := &keyRing{current: &keyEntry{kid: "a"}, all: []*keyEntry{{kid: "a"}}}
+	b := &keyRing{current: &keyEntry{kid: "b"}, all: []*keyEntry{{kid: "a"}, {kid: "b"}}}
+
+	a.replace(b)
+
+	if a.current.kid != "b" {
+		t.Errorf("a.current.kid = %q, want %q", a.current.kid, "b")
+	}
+	if len(a.all) != 2 {
+		t.Errorf("len(a.all) = %d, want 2", len(a.all))
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+
+	"gopkg.in/square/go-jose.v2"
+)
+
+// signingAlgorithms maps a -alg flag value to its go-jose identifier.
+var signingAlgorithms = map[string]jose.SignatureAlgorithm{
+	"HS256": jose.HS256,
+	"RS256": jose.RS256,
+	"ES256": jose.ES256,
+	"EdDSA": jose.EdDSA,
+}
+
+// loadPrivateKey reads a PEM encoded private key from path: PKCS#8 (RSA,
+// ECDSA, or Ed25519), or the PKCS#1/SEC1 formats openssl produces by
+// default.
+func loadPrivateKey(path string) (crypto.Signer, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading signing key: %w", err)
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, fmt.Errorf("%s does not contain a PEM block", path)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		if rsaKey, rsaErr := x509.ParsePKCS1PrivateKey(block.Bytes); rsaErr == nil {
+			key = rsaKey
+		} else if ecKey, ecErr := x509.ParseECPrivateKey(block.Bytes); ecErr == nil {
+			key = ecKey
+		} else {
+			return nil, fmt.Errorf("parsing signing key: %w", err)
+		}
+	}
+
+	switch key.(type) {
+	case *rsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey:
+	default:
+		return nil, fmt.Errorf("%s is not an RSA, ECDSA, or Ed25519 private key", path)
+	}
+	return key.(crypto.Signer), nil
+}
+
+// loadPublicKey reads a PEM encoded PKIX public key from path, for
+// verifying tokens signed with an asymmetric algorithm.
+func loadPublicKey(path string) (crypto.PublicKey, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading public key: %w", err)
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, fmt.Errorf("%s does not contain a PEM block", path)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing public key: %w", err)
+	}
+	return key, nil
+}
+
+// keyID derives a stable "kid" for pub by hashing its DER encoded form.
+func keyID(pub crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", fmt.Errorf("marshaling public key: %w", err)
+	}
+	sum := sha256.Sum256(der)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// newSigner builds a jose.Signer for alg. HS256 signs with sharedKey; the
+// asymmetric algorithms sign with the key at signingKeyPath and stamp a
+// "kid" header derived from its public key.
+func newSigner(alg jose.SignatureAlgorithm, sharedKey []byte, signingKeyPath string) (jose.Signer, error) {
+	if alg == jose.HS256 {
+		signer, err := jose.NewSigner(jose.SigningKey{Algorithm: alg, Key: sharedKey}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("bad shared key: %w", err)
+		}
+		return signer, nil
+	}
+
+	priv, err := loadPrivateKey(signingKeyPath)
+	if err != nil {
+		return nil, err
+	}
+	kid, err := keyID(priv.Public())
+	if err != nil {
+		return nil, err
+	}
+	so := (&jose.SignerOptions{}).WithHeader("kid", kid)
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: alg, Key: priv}, so)
+	if err != nil {
+		return nil, fmt.Errorf("bad signing key for %s: %w", alg, err)
+	}
+	return signer, nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/square/go-jose.v2"
+)
+
+func writeKeyFile(t *testing.T, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "key.pem")
+	block := &pem.Block{Type: blockType, Bytes: der}
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatalf("writing key: %s", err)
+	}
+	return path
+}
+
+func TestLoadPrivateKeyFormats(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating rsa key: %s", err)
+	}
+
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(rsaKey)
+	if err != nil {
+		t.Fatalf("marshaling pkcs8: %s", err)
+	}
+	pkcs1 := x509.MarshalPKCS1PrivateKey(rsaKey)
+
+	for name, path := range map[string]string{
+		"pkcs8": writeKeyFile(t, "PRIVATE KEY", pkcs8),
+		"pkcs1": writeKeyFile(t, "RSA PRIVATE KEY", pkcs1),
+	} {
+		if _, err := loadPrivateKey(path); err != nil {
+			t.Errorf("%s: loadPrivateKey: %s", name, err)
+		}
+	}
+}
+
+func TestLoadPrivateKeyMalformed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, []byte("not a pem file"), 0o600); err != nil {
+		t.Fatalf("writing key: %s", err)
+	}
+	if _, err := loadPrivateKey(path); err == nil {
+		t.Fatal("expected an error for a non-PEM file")
+	}
+
+	garbage := writeKeyFile(t, "PRIVATE KEY", []byte("not valid DER"))
+	if _, err := loadPrivateKey(garbage); err == nil {
+		t.Fatal("expected an error for a PEM block with invalid DER")
+	}
+
+	if _, err := loadPrivateKey(filepath.Join(t.TempDir(), "missing.pem")); err == nil {
+		t.Fatal("expected an error for a missing file")
+	}
+}
+
+func TestKeyIDStableForSameKey(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generating key: %s", err)
+	}
+	a, err := keyID(priv.Public())
+	if err != nil {
+		t.Fatalf("keyID: %s", err)
+	}
+	b, err := keyID(priv.Public())
+	if err != nil {
+		t.Fatalf("keyID: %s", err)
+	}
+	if a != b {
+		t.Errorf("keyID not stable: %q != %q", a, b)
+	}
+
+	_, other, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generating key: %s", err)
+	}
+	c, err := keyID(other.Public())
+	if err != nil {
+		t.Fatalf("keyID: %s", err)
+	}
+	if a == c {
+		t.Error("keyID collided for two different keys")
+	}
+}
+
+func TestNewSignerHS256(t *testing.T) {
+	signer, err := newSigner(jose.HS256, []byte("a-shared-secret"), "")
+	if err != nil {
+		t.Fatalf("newSigner: %s", err)
+	}
+	if signer == nil {
+		t.Fatal("newSigner returned a nil signer")
+	}
+}
+
+func TestNewSignerRequiresSigningKeyForAsymmetric(t *testing.T) {
+	if _, err := newSigner(jose.RS256, nil, ""); err == nil {
+		t.Fatal("expected an error when -signing-key is empty for an asymmetric algorithm")
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bufio"
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+// runVerify checks a token's signature and standard claims, returning a
+// non-nil error for any invalid token so the exit code signals validity.
+func runVerify(args []string) error {
+	fs := flag.NewFlagSet("pomerium-sa verify", flag.ExitOnError)
+	flags = fs
+	token := fs.String("token", "", "Compact JWT to verify (default: read from stdin)")
+	sharedKey := fs.String("shared-key", "", "Base64 encoded shared key (HS256)")
+	keyPath := fs.String("key", "", "Path to a PEM encoded public key (RS256, ES256, or EdDSA)")
+	jwksURL := fs.String("jwks-url", "", "URL of a JWKS document to verify against, matched by kid")
+	iss := fs.String("iss", "", "Expected issuer")
+	var aud commaSlice
+	fs.Var(&aud, "aud", "Expected audience (e.g. httpbin.int.pomerium.io,prometheus.int.pomerium.io; optional)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *iss == "" {
+		return errors.New("-iss is required")
+	}
+
+	raw := *token
+	if raw == "" {
+		scanner := bufio.NewScanner(os.Stdin)
+		scanner.Scan()
+		raw = strings.TrimSpace(scanner.Text())
+	}
+	if raw == "" {
+		return errors.New("a token is required, via -token or stdin")
+	}
+
+	parsed, err := jwt.ParseSigned(raw)
+	if err != nil {
+		return fmt.Errorf("parsing jwt: %w", err)
+	}
+
+	key, err := verificationKey(parsed, *sharedKey, *keyPath, *jwksURL)
+	if err != nil {
+		return err
+	}
+
+	var sa serviceAccount
+	if err := parsed.Claims(key, &sa); err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+
+	expected := jwt.Expected{
+		Issuer: *iss,
+		Time:   time.Now(),
+	}
+	if len(aud) > 0 {
+		expected.Audience = jwt.Audience(aud)
+	}
+	if err := sa.Claims.Validate(expected); err != nil {
+		return fmt.Errorf("invalid claims: %w", err)
+	}
+
+	enc, err := json.MarshalIndent(sa, "", " ")
+	if err != nil {
+		return fmt.Errorf("couldn't pretty print claims: %w", err)
+	}
+	c := color.New(color.FgGreen)
+	if _, err := c.Println("Service Account"); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "%s\n\n", enc)
+	if _, err := c.Println("✅ valid"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// verificationKey resolves exactly one of -shared-key, -key, or -jwks-url.
+func verificationKey(parsed *jwt.JSONWebToken, sharedKey, keyPath, jwksURL string) (interface{}, error) {
+	set := 0
+	for _, v := range []string{sharedKey, keyPath, jwksURL} {
+		if v != "" {
+			set++
+		}
+	}
+	if set == 0 {
+		return nil, errors.New("one of -shared-key, -key, or -jwks-url is required")
+	}
+	if set > 1 {
+		return nil, errors.New("only one of -shared-key, -key, or -jwks-url may be given")
+	}
+
+	switch {
+	case sharedKey != "":
+		decoded, err := base64.StdEncoding.DecodeString(sharedKey)
+		if err != nil {
+			return nil, fmt.Errorf("shared key not base64: %w", err)
+		}
+		return decoded, nil
+	case keyPath != "":
+		return loadPublicKey(keyPath)
+	default:
+		return jwksKey(jwksURL, parsed)
+	}
+}
+
+// jwksFetchTimeout bounds the /jwks.json request so a slow or unresponsive
+// authenticate service can't hang verification indefinitely (CI in
+// particular can't tolerate an unbounded hang).
+const jwksFetchTimeout = 10 * time.Second
+
+// jwksKey fetches the JWKS document at jwksURL and returns the public key
+// whose "kid" matches the one stamped in the token's header.
+func jwksKey(jwksURL string, parsed *jwt.JSONWebToken) (crypto.PublicKey, error) {
+	if len(parsed.Headers) == 0 {
+		return nil, errors.New("token has no JOSE header")
+	}
+	kid := parsed.Headers[0].KeyID
+	if kid == "" {
+		return nil, errors.New("token has no kid header to match against the jwks")
+	}
+
+	client := &http.Client{Timeout: jwksFetchTimeout}
+	resp, err := client.Get(jwksURL)
+	if err != nil {
+		return nil, fmt.Errorf("fetching jwks: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching jwks: unexpected status %s", resp.Status)
+	}
+
+	var set jose.JSONWebKeySet
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("decoding jwks: %w", err)
+	}
+	for _, k := range set.Keys {
+		if k.KeyID == kid {
+			return k.Key, nil
+		}
+	}
+	return nil, fmt.Errorf("no key with kid %q in jwks", kid)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+// TestMintJWKSVerifyRoundTrip exercises the full asymmetric path: sign a
+// token the way "-alg RS256 -signing-key" would, publish it the way "jwks" would,
+// and check it the way "verify -jwks-url" would.
+func TestMintJWKSVerifyRoundTrip(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating rsa key: %s", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(rsaKey)
+	if err != nil {
+		t.Fatalf("marshaling key: %s", err)
+	}
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("writing key: %s", err)
+	}
+
+	signer, err := newSigner(jose.RS256, nil, path)
+	if err != nil {
+		t.Fatalf("newSigner: %s", err)
+	}
+
+	want := serviceAccount{Email: "user@example.com"}
+	want.Issuer = "authenticate.example.com"
+	want.Audience = jwt.Audience{"httpbin.example.com"}
+	want.Expiry = jwt.NewNumericDate(time.Now().Add(time.Hour))
+	want.IssuedAt = jwt.NewNumericDate(time.Now())
+	want.NotBefore = jwt.NewNumericDate(time.Now())
+
+	raw, err := jwt.Signed(signer).Claims(want).CompactSerialize()
+	if err != nil {
+		t.Fatalf("signing jwt: %s", err)
+	}
+
+	priv, err := loadPrivateKey(path)
+	if err != nil {
+		t.Fatalf("loadPrivateKey: %s", err)
+	}
+	kid, err := keyID(priv.Public())
+	if err != nil {
+		t.Fatalf("keyID: %s", err)
+	}
+	jwks := jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{
+		Key:       priv.Public(),
+		KeyID:     kid,
+		Algorithm: string(jose.RS256),
+		Use:       "sig",
+	}}}
+
+	parsed, err := jwt.ParseSigned(raw)
+	if err != nil {
+		t.Fatalf("parsing jwt: %s", err)
+	}
+	if len(parsed.Headers) == 0 || parsed.Headers[0].KeyID != kid {
+		t.Fatalf("token kid header = %q, want %q", parsed.Headers[0].KeyID, kid)
+	}
+
+	var key interface{}
+	for _, k := range jwks.Keys {
+		if k.KeyID == parsed.Headers[0].KeyID {
+			key = k.Key
+		}
+	}
+	if key == nil {
+		t.Fatal("no matching key in jwks")
+	}
+
+	var got serviceAccount
+	if err := parsed.Claims(key, &got); err != nil {
+		t.Fatalf("invalid signature: %s", err)
+	}
+	if err := got.Claims.Validate(jwt.Expected{Issuer: want.Issuer, Time: time.Now()}); err != nil {
+		t.Fatalf("invalid claims: %s", err)
+	}
+	if got.Email != want.Email {
+		t.Errorf("Email = %q, want %q", got.Email, want.Email)
+	}
+}
+
+func TestVerificationKeyRequiresExactlyOneSource(t *testing.T) {
+	if _, err := verificationKey(nil, "", "", ""); err == nil {
+		t.Fatal("expected an error when no verification source is given")
+	}
+	if _, err := verificationKey(nil, "a2V5", "/tmp/foo", ""); err == nil {
+		t.Fatal("expected an error when more than one verification source is given")
+	}
+}